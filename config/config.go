@@ -0,0 +1,105 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Duration wraps time.Duration so it can be configured as a human readable
+// string (e.g. "5m") in the JSON configuration file.
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var v string
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	duration, err := time.ParseDuration(v)
+	if err != nil {
+		return fmt.Errorf("could not parse duration %q: %s", v, err)
+	}
+	d.Duration = duration
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.Duration.String())
+}
+
+// Home describes a tado home to collect zone readings for.
+type Home struct {
+	Id   int
+	Name string
+}
+
+// Influx holds the connection settings for the InfluxDB output backend.
+// Version selects which client is used: "v1" (database/username/password,
+// the default) or "v2" (token/org/bucket). When Version is empty it is
+// inferred from the presence of Token/Bucket vs Database/Username.
+type Influx struct {
+	Version  string
+	Url      string
+	Database string
+	Username string
+	Password string
+	Token    string
+	Org      string
+	Bucket   string
+}
+
+// Prometheus holds the settings for the embedded metrics HTTP server. It is
+// disabled unless Listen is set.
+type Prometheus struct {
+	Listen string
+}
+
+// MQTT holds the connection settings for the MQTT output backend. It is
+// disabled unless Broker is set.
+type MQTT struct {
+	Broker      string
+	ClientID    string
+	Username    string
+	Password    string
+	TopicPrefix string
+	QoS         byte
+	Retain      bool
+}
+
+// Config is the structure of the gotadoflux configuration file.
+type Config struct {
+	Username string
+	Password string
+	// AuthMode selects the Tado authentication flow: "password" (default,
+	// resource-owner password grant) or "device" (OAuth 2.0 device
+	// authorization grant, RFC 8628).
+	AuthMode         string
+	RefreshTokenPath string
+	// TokenStore selects where the refresh token is persisted: "file" (the
+	// default), "keyring" (the OS-native credential manager), or "auto" to
+	// prefer the keyring and fall back to a file.
+	TokenStore string
+	Period     Duration
+	// Proxy, when set, is used for all requests to the Tado API instead of
+	// the HTTPS_PROXY/ALL_PROXY environment variables (SOCKS5 included).
+	Proxy   string
+	NoProxy string
+	// Timeout bounds a single HTTP request/response round trip.
+	Timeout             Duration
+	MaxIdleConns        int
+	IdleConnTimeout     Duration
+	TLSHandshakeTimeout Duration
+	// MaxRetries is how many times a Tado API request is retried on network
+	// errors or HTTP 5xx/429 responses, with exponential backoff.
+	MaxRetries int
+	// MaxBufferedPoints caps how many collected points are kept in memory
+	// while a write backend is unavailable, dropping the oldest once
+	// exceeded. Zero means unbounded.
+	MaxBufferedPoints int
+	Influx            Influx
+	MQTT              MQTT
+	Prometheus        Prometheus
+	Collect           []Home
+}
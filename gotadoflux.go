@@ -15,25 +15,27 @@ import (
 	"time"
 
 	"github.com/cblomart/gotadoflux/config"
+	"github.com/cblomart/gotadoflux/metrics"
 	"github.com/cblomart/gotadoflux/tado"
+	"github.com/cblomart/gotadoflux/writer"
 
-	client "github.com/influxdata/influxdb1-client/v2"
 	"github.com/takama/daemon"
 )
 
 var (
-	conf         = &config.Config{}
-	tadoclient   *tado.Tado
-	zones        = map[int]map[string]string{}
-	influx       client.Client
-	points       = []*client.Point{}
-	dependencies = []string{}
-	lastSync     = time.Time{}
+	conf          = &config.Config{}
+	tadoclient    *tado.Tado
+	zones         = map[int]map[string]string{}
+	writers       = []writer.Writer{}
+	pendingPoints = map[writer.Writer][]writer.Point{}
+	dependencies  = []string{}
+	lastSync      = time.Time{}
 )
 
 // function to collect data
 func collect() {
 	log.Println("retrieving data from tado")
+	newPoints := []writer.Point{}
 	for _, home := range conf.Collect {
 		states, err := tadoclient.GetZoneStates(home.Id)
 		if err != nil {
@@ -84,45 +86,50 @@ func collect() {
 			if state.ActivityDataPoints.HeatingPower != nil {
 				powerPc = *state.ActivityDataPoints.HeatingPower.Percentage
 			}
-			point, err := client.NewPoint(
-				name,
-				map[string]string{
+			point := writer.Point{
+				Name: name,
+				Tags: map[string]string{
 					"homeId":   strconv.Itoa(home.Id),
 					"homeName": home.Name,
 					"zoneId":   zoneId,
 					"zoneName": zoneName,
 					"source":   "tado",
 				},
-				map[string]interface{}{
+				Fields: map[string]interface{}{
 					"temperature": *state.SensorDataPoints.InsideTemperature.Celsius,
 					"humidity":    *state.SensorDataPoints.Humidity.Percentage,
 					"power":       powerPc,
 				},
-				*state.SensorDataPoints.InsideTemperature.Timestamp,
-			)
-			if err != nil {
-				log.Printf("could not create point for %s", name)
+				Time: *state.SensorDataPoints.InsideTemperature.Timestamp,
 			}
-			points = append(points, point)
+			newPoints = append(newPoints, point)
 		}
 	}
-	bps, err := client.NewBatchPoints(client.BatchPointsConfig{
-		Precision: "s",
-		Database:  conf.Influx.Database,
-	})
-	bps.AddPoints(points)
-	if err != nil {
-		log.Println("cloud not create batchpoints")
-		return
+	// advance lastSync as soon as the data is collected: per-writer delivery
+	// failures are tracked independently below, so a struggling writer must
+	// not make us re-collect (and re-buffer for every writer) the same
+	// readings a writer that already succeeded has delivered.
+	lastSync = time.Now()
+	for _, w := range writers {
+		pendingPoints[w] = append(pendingPoints[w], newPoints...)
 	}
-	err = influx.Write(bps)
-	if err != nil {
-		log.Printf("cloud not write to influx: %s", err)
-		return
+	for _, w := range writers {
+		pending := pendingPoints[w]
+		if len(pending) == 0 {
+			continue
+		}
+		if err := w.Write(pending); err != nil {
+			log.Printf("could not write points, keeping them buffered for next tick: %s", err)
+			if conf.MaxBufferedPoints > 0 && len(pending) > conf.MaxBufferedPoints {
+				dropped := len(pending) - conf.MaxBufferedPoints
+				log.Printf("point buffer over %d, dropping %d oldest point(s)", conf.MaxBufferedPoints, dropped)
+				pendingPoints[w] = pending[dropped:]
+			}
+			continue
+		}
+		log.Printf("written %d points to writer", len(pending))
+		pendingPoints[w] = nil
 	}
-	lastSync = time.Now()
-	log.Printf("written %d points to influx", len(points))
-	points = nil
 }
 
 // Service has embedded daemon
@@ -169,20 +176,43 @@ func (service *Service) Manage() (string, error) {
 	}
 
 	// preparing the influx connection
-	influx, err = client.NewHTTPClient(client.HTTPConfig{
-		Addr:     conf.Influx.Url,
-		Username: conf.Influx.Username,
-		Password: conf.Influx.Password,
-	})
-	if err != nil {
-		return fmt.Sprintf("could not instanciate the influx client"), err
+	if len(conf.Influx.Url) > 0 {
+		influxWriter, err := writer.NewInflux(conf.Influx)
+		if err != nil {
+			return fmt.Sprintf("could not instanciate the influx client"), err
+		}
+		if err = influxWriter.Ping(); err != nil {
+			return fmt.Sprintf("test connection to influx failed"), err
+		}
+		log.Printf("connected to influx %s", conf.Influx.Url)
+		defer influxWriter.Close()
+		writers = append(writers, influxWriter)
 	}
-	_, ver, err := influx.Ping(5 * time.Second)
-	if err != nil {
-		return fmt.Sprintf("test connection to influx failed"), err
+
+	// preparing the mqtt connection
+	if len(conf.MQTT.Broker) > 0 {
+		mqttWriter, err := writer.NewMQTTWriter(conf.MQTT)
+		if err != nil {
+			return fmt.Sprintf("could not instanciate the mqtt client"), err
+		}
+		if err = mqttWriter.Ping(); err != nil {
+			return fmt.Sprintf("test connection to mqtt failed"), err
+		}
+		log.Printf("connected to mqtt %s", conf.MQTT.Broker)
+		defer mqttWriter.Close()
+		writers = append(writers, mqttWriter)
+	}
+
+	// preparing the prometheus endpoint
+	if len(conf.Prometheus.Listen) > 0 {
+		go func() {
+			log.Printf("serving metrics on %s/metrics", conf.Prometheus.Listen)
+			if err := metrics.Serve(conf.Prometheus.Listen); err != nil {
+				log.Printf("metrics server stopped: %s", err)
+			}
+		}()
+		writers = append(writers, writer.NewPrometheusWriter())
 	}
-	log.Printf("connected to influx %s (%s)", conf.Influx.Url, ver)
-	defer influx.Close()
 
 	//initial collection
 	collect()
@@ -231,21 +261,25 @@ func main() {
 		conf.RefreshTokenPath = fmt.Sprintf("%s.token", configname)
 	}
 
-	// create token cache if file does not exist
-	refreshTokenPath, err := os.Stat(conf.RefreshTokenPath)
-	if os.IsNotExist(err) {
-		file, err := os.Create(conf.RefreshTokenPath)
-		if err != nil {
-			log.Fatalf("could not create file: %s", conf.RefreshTokenPath)
-		}
-		err = file.Chmod(os.FileMode(int(0600)))
-		if err != nil {
-			log.Fatalf("could not set file mode to 0600: %s", conf.RefreshTokenPath)
+	// the file and keyring-with-dpapi-fallback backends cache the token in a
+	// local file; the keyring backends on other platforms don't need one
+	if conf.TokenStore == "" || conf.TokenStore == "file" {
+		// create token cache if file does not exist
+		refreshTokenPath, err := os.Stat(conf.RefreshTokenPath)
+		if os.IsNotExist(err) {
+			file, err := os.Create(conf.RefreshTokenPath)
+			if err != nil {
+				log.Fatalf("could not create file: %s", conf.RefreshTokenPath)
+			}
+			err = file.Chmod(os.FileMode(int(0600)))
+			if err != nil {
+				log.Fatalf("could not set file mode to 0600: %s", conf.RefreshTokenPath)
+			}
+			file.Close()
+		} else if refreshTokenPath.Mode() != os.FileMode(int(0600)) && runtime.GOOS != "windows" {
+			// on windows token will be protected by DPAPI
+			log.Fatalf("token cache should have 0600 mode: %s", conf.RefreshTokenPath)
 		}
-		file.Close()
-	} else if refreshTokenPath.Mode() != os.FileMode(int(0600)) && runtime.GOOS != "windows" {
-		// on windows token will be protected by DPAPI
-		log.Fatalf("token cache should have 0600 mode: %s", conf.RefreshTokenPath)
 	}
 
 	// create the daemon
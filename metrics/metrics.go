@@ -0,0 +1,52 @@
+// Package metrics exposes the Prometheus metrics gotadoflux can be scraped
+// for: per-zone gauges updated on every collection tick, and counters/
+// histograms tracking the health of the Tado API itself.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	ZoneTemperature = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "tado_zone_temperature_celsius",
+		Help: "Last reported inside temperature of a tado zone, in celsius.",
+	}, []string{"home", "zone", "zone_id"})
+
+	ZoneHumidity = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "tado_zone_humidity_percent",
+		Help: "Last reported humidity of a tado zone, in percent.",
+	}, []string{"home", "zone", "zone_id"})
+
+	ZoneHeatingPower = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "tado_zone_heating_power_percent",
+		Help: "Last reported heating power of a tado zone, in percent.",
+	}, []string{"home", "zone", "zone_id"})
+
+	ZoneLastUpdate = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "tado_zone_last_update_timestamp_seconds",
+		Help: "Timestamp of the last reading collected for a tado zone.",
+	}, []string{"home", "zone", "zone_id"})
+
+	APIRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "tado_api_requests_total",
+		Help: "Total number of requests made to the Tado API, by endpoint and status.",
+	}, []string{"endpoint", "status"})
+
+	APIRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "tado_api_request_duration_seconds",
+		Help: "Duration of requests made to the Tado API, by endpoint.",
+	}, []string{"endpoint"})
+)
+
+// Serve starts an HTTP server exposing the registered metrics on /metrics at
+// the given address. It blocks and should be run in its own goroutine.
+func Serve(listen string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	return http.ListenAndServe(listen, mux)
+}
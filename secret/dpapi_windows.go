@@ -0,0 +1,18 @@
+//go:build windows
+
+package secret
+
+// DPAPIStore persists the refresh token to a local file encrypted with the
+// Windows Data Protection API (the long-standing behaviour of FileStore on
+// Windows, named here for explicit selection via TokenStore).
+type DPAPIStore struct {
+	FileStore
+}
+
+func NewDPAPIStore(path string) *DPAPIStore {
+	return &DPAPIStore{*NewFileStore(path)}
+}
+
+func newKeyringStore(account string) (Store, error) {
+	return NewDPAPIStore(account), nil
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package secret
+
+// tokenEncrypt is a no-op on non-Windows platforms: FileStore's refresh
+// token cache is protected by its 0600 file mode check instead.
+func tokenEncrypt(token string) string {
+	return token
+}
+
+// tokenDecrypt reverses tokenEncrypt.
+func tokenDecrypt(token string) string {
+	return token
+}
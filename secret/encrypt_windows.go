@@ -0,0 +1,24 @@
+//go:build windows
+
+package secret
+
+import "github.com/billgraziano/dpapi"
+
+// tokenEncrypt protects the refresh token cache file at rest using the
+// Windows Data Protection API.
+func tokenEncrypt(token string) string {
+	encrypted, err := dpapi.Encrypt(token)
+	if err != nil {
+		return token
+	}
+	return encrypted
+}
+
+// tokenDecrypt reverses tokenEncrypt.
+func tokenDecrypt(token string) string {
+	decrypted, err := dpapi.Decrypt(token)
+	if err != nil {
+		return token
+	}
+	return decrypted
+}
@@ -0,0 +1,27 @@
+package secret
+
+const (
+	TokenStoreFile    = "file"
+	TokenStoreKeyring = "keyring"
+	TokenStoreAuto    = "auto"
+)
+
+// New builds the Store selected by tokenStore: "file" (the default, for
+// backwards compatibility), "keyring" for the platform's native credential
+// manager (Keychain, Secret Service, or the Windows store), or "auto" to
+// prefer the keyring and fall back to file storage.
+func New(tokenStore, path string) (Store, error) {
+	switch tokenStore {
+	case TokenStoreKeyring:
+		return newKeyringStore(path)
+	case TokenStoreAuto:
+		if store, err := newKeyringStore(path); err == nil {
+			return store, nil
+		}
+		return NewFileStore(path), nil
+	case TokenStoreFile, "":
+		return NewFileStore(path), nil
+	default:
+		return NewFileStore(path), nil
+	}
+}
@@ -0,0 +1,67 @@
+package secret
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"runtime"
+)
+
+// FileStore persists the refresh token to a local file, protected by a 0600
+// file mode (and, on Windows, by DPAPI encryption).
+type FileStore struct {
+	path string
+}
+
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+func (s *FileStore) Get() (string, error) {
+	fileInfo, err := os.Stat(s.path)
+	if err != nil {
+		return "", err
+	}
+	if fileInfo.Mode() != os.FileMode(0600) && runtime.GOOS != "windows" {
+		return "", fmt.Errorf("refresh token cache is not properly protected")
+	}
+	if fileInfo.Size() == 0 {
+		return "", nil
+	}
+	raw, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		return "", err
+	}
+	stored, err := decodeStoredToken([]byte(tokenDecrypt(string(raw))))
+	if err != nil {
+		return "", err
+	}
+	if stored.expired() {
+		return "", nil
+	}
+	return stored.Token, nil
+}
+
+func (s *FileStore) Has() bool {
+	token, err := s.Get()
+	return err == nil && len(token) > 0
+}
+
+func (s *FileStore) Save(token string) error {
+	if len(token) == 0 {
+		return fmt.Errorf("Cannot save empty refresh token")
+	}
+	raw, err := encodeStoredToken(token)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(s.path, []byte(tokenEncrypt(string(raw))), os.FileMode(0600)); err != nil {
+		return err
+	}
+	// WriteFile only applies the given mode when it creates the file; if the
+	// path already existed with a looser mode (or the umask weakened the
+	// mode on creation), Get's 0600 check would then lock us out of the
+	// token we just wrote. Chmod unconditionally so Save never depends on
+	// the caller having pre-created the file with the right mode.
+	return os.Chmod(s.path, os.FileMode(0600))
+}
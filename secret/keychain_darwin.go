@@ -0,0 +1,20 @@
+//go:build darwin
+
+package secret
+
+// KeychainStore persists the refresh token in the macOS Keychain.
+type KeychainStore struct {
+	keyringStore
+}
+
+func NewKeychainStore(account string) *KeychainStore {
+	return &KeychainStore{keyringStore{service: "gotadoflux", account: account}}
+}
+
+func newKeyringStore(account string) (Store, error) {
+	store := NewKeychainStore(account)
+	if err := store.probe(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
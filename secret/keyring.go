@@ -0,0 +1,59 @@
+package secret
+
+import (
+	"github.com/zalando/go-keyring"
+)
+
+// keyringStore persists the refresh token in the OS-native credential
+// manager via github.com/zalando/go-keyring (Keychain on macOS, Secret
+// Service on Linux, the credential manager on Windows). It is embedded by
+// the platform-specific store types so each keeps its own name, matching
+// what it is backed by on that OS.
+type keyringStore struct {
+	service string
+	account string
+}
+
+func (s keyringStore) Get() (string, error) {
+	raw, err := keyring.Get(s.service, s.account)
+	if err == keyring.ErrNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	stored, err := decodeStoredToken([]byte(raw))
+	if err != nil {
+		return "", err
+	}
+	if stored.expired() {
+		return "", nil
+	}
+	return stored.Token, nil
+}
+
+func (s keyringStore) Has() bool {
+	token, err := s.Get()
+	return err == nil && len(token) > 0
+}
+
+func (s keyringStore) Save(token string) error {
+	raw, err := encodeStoredToken(token)
+	if err != nil {
+		return err
+	}
+	return keyring.Set(s.service, s.account, string(raw))
+}
+
+// probeAccount is a throwaway entry used by probe to verify the native
+// credential manager is actually reachable (e.g. a headless Linux box with
+// no D-Bus session has the Secret Service package linked in but no backend
+// to talk to) before it is committed to as the store for TokenStoreAuto.
+const probeAccount = "gotadoflux-probe"
+
+func (s keyringStore) probe() error {
+	if err := keyring.Set(s.service, probeAccount, "probe"); err != nil {
+		return err
+	}
+	return keyring.Delete(s.service, probeAccount)
+}
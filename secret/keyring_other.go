@@ -0,0 +1,10 @@
+//go:build !darwin && !linux && !windows
+
+package secret
+
+// newKeyringStore has no native credential manager to back it on this
+// platform, so TokenStoreKeyring/TokenStoreAuto fall back to FileStore,
+// the same as an explicit TokenStoreFile.
+func newKeyringStore(path string) (Store, error) {
+	return NewFileStore(path), nil
+}
@@ -0,0 +1,43 @@
+// Package secret persists the Tado OAuth refresh token across restarts,
+// behind a Store interface so the backing storage (a local file, or the
+// OS-native credential manager) can be swapped per platform.
+package secret
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Store is implemented by each refresh token backend.
+type Store interface {
+	Has() bool
+	Get() (string, error)
+	Save(token string) error
+}
+
+// refreshTokenMaxAge bounds how long a cached refresh token is trusted
+// before AuthCheck is forced to re-authenticate from scratch.
+const refreshTokenMaxAge = 180 * 24 * time.Hour
+
+// storedToken is the payload persisted by every Store implementation: the
+// token itself plus when it was issued, so expiry can be judged from data
+// rather than from storage metadata (a file's mtime is meaningless once the
+// token lives in an OS keyring).
+type storedToken struct {
+	Token    string    `json:"token"`
+	IssuedAt time.Time `json:"issued_at"`
+}
+
+func (s storedToken) expired() bool {
+	return s.IssuedAt.Add(refreshTokenMaxAge).Before(time.Now())
+}
+
+func encodeStoredToken(token string) ([]byte, error) {
+	return json.Marshal(storedToken{Token: token, IssuedAt: time.Now()})
+}
+
+func decodeStoredToken(raw []byte) (storedToken, error) {
+	stored := storedToken{}
+	err := json.Unmarshal(raw, &stored)
+	return stored, err
+}
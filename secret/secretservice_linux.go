@@ -0,0 +1,21 @@
+//go:build linux
+
+package secret
+
+// SecretServiceStore persists the refresh token in the Linux Secret
+// Service (libsecret), e.g. GNOME Keyring or KWallet.
+type SecretServiceStore struct {
+	keyringStore
+}
+
+func NewSecretServiceStore(account string) *SecretServiceStore {
+	return &SecretServiceStore{keyringStore{service: "gotadoflux", account: account}}
+}
+
+func newKeyringStore(account string) (Store, error) {
+	store := NewSecretServiceStore(account)
+	if err := store.probe(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
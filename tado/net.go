@@ -0,0 +1,96 @@
+package tado
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// newFormRequest builds a POST request with a form-urlencoded body. It is
+// used as the request factory passed to doWithRetry, since a request body
+// can only be read once and must be rebuilt on every retry attempt.
+func newFormRequest(urlStr string, params url.Values) (*http.Request, error) {
+	request, err := http.NewRequest("POST", urlStr, strings.NewReader(params.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return request, nil
+}
+
+// newAuthorizedGet builds a bearer-authenticated GET request.
+func newAuthorizedGet(urlStr, accessToken string) (*http.Request, error) {
+	request, err := http.NewRequest("GET", urlStr, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Authorization", "Bearer "+accessToken)
+	return request, nil
+}
+
+// HTTPOptions configures the transport used by a Tado client: proxying,
+// connection pooling and the retry budget applied by doWithRetry. Zero
+// values fall back to the defaults below rather than meaning "unlimited",
+// so a config that doesn't set these fields still gets a client at least
+// as safe as the bare http.DefaultTransport it replaces.
+type HTTPOptions struct {
+	Proxy               string
+	NoProxy             string
+	Timeout             time.Duration
+	MaxIdleConns        int
+	IdleConnTimeout     time.Duration
+	TLSHandshakeTimeout time.Duration
+	MaxRetries          int
+}
+
+const (
+	defaultDialTimeout         = 30 * time.Second
+	defaultClientTimeout       = 30 * time.Second
+	defaultMaxIdleConns        = 100
+	defaultIdleConnTimeout     = 90 * time.Second
+	defaultTLSHandshakeTimeout = 10 * time.Second
+)
+
+// buildTransport honours HTTPS_PROXY/ALL_PROXY (including SOCKS5, via
+// golang.org/x/net/proxy) from the environment, or the explicit Proxy/NoProxy
+// options when set.
+func buildTransport(opts HTTPOptions) *http.Transport {
+	if len(opts.NoProxy) > 0 {
+		os.Setenv("NO_PROXY", opts.NoProxy)
+	}
+	dialer := &net.Dialer{Timeout: defaultDialTimeout}
+	socksDialer := proxy.FromEnvironmentUsing(dialer)
+	tlsHandshakeTimeout := opts.TLSHandshakeTimeout
+	if tlsHandshakeTimeout == 0 {
+		tlsHandshakeTimeout = defaultTLSHandshakeTimeout
+	}
+	maxIdleConns := opts.MaxIdleConns
+	if maxIdleConns == 0 {
+		maxIdleConns = defaultMaxIdleConns
+	}
+	idleConnTimeout := opts.IdleConnTimeout
+	if idleConnTimeout == 0 {
+		idleConnTimeout = defaultIdleConnTimeout
+	}
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return socksDialer.Dial(network, addr)
+		},
+		MaxIdleConns:        maxIdleConns,
+		IdleConnTimeout:     idleConnTimeout,
+		TLSHandshakeTimeout: tlsHandshakeTimeout,
+	}
+	if len(opts.Proxy) > 0 {
+		if proxyURL, err := url.Parse(opts.Proxy); err == nil {
+			transport.Proxy = http.ProxyURL(proxyURL)
+		}
+	}
+	return transport
+}
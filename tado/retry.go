@@ -0,0 +1,60 @@
+package tado
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cblomart/gotadoflux/metrics"
+)
+
+const (
+	retryBaseBackoff = 1 * time.Second
+	retryMaxBackoff  = 60 * time.Second
+)
+
+// doWithRetry sends the request built by newRequest, retrying on network
+// errors and HTTP 5xx/429 with exponential backoff (1s, 2s, 4s, 8s, ...
+// capped at 60s), honouring a Retry-After header on 429. The request is
+// rebuilt on every attempt since a request body can only be read once.
+// endpoint labels the tado_api_requests_total/tado_api_request_duration_seconds
+// metrics recorded for every attempt.
+func (t *Tado) doWithRetry(endpoint string, newRequest func() (*http.Request, error)) (*http.Response, error) {
+	backoff := retryBaseBackoff
+	var lastErr error
+	for attempt := 0; attempt <= t.maxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+			if backoff > retryMaxBackoff {
+				backoff = retryMaxBackoff
+			}
+		}
+		req, err := newRequest()
+		if err != nil {
+			return nil, err
+		}
+		start := time.Now()
+		resp, err := t.client.Do(req)
+		metrics.APIRequestDuration.WithLabelValues(endpoint).Observe(time.Since(start).Seconds())
+		if err != nil {
+			lastErr = err
+			metrics.APIRequestsTotal.WithLabelValues(endpoint, "error").Inc()
+			continue
+		}
+		metrics.APIRequestsTotal.WithLabelValues(endpoint, strconv.Itoa(resp.StatusCode)).Inc()
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode/100 == 5 {
+			lastErr = fmt.Errorf("tado api returned status %d", resp.StatusCode)
+			if retryAfter := resp.Header.Get("Retry-After"); len(retryAfter) > 0 {
+				if seconds, err := strconv.Atoi(retryAfter); err == nil {
+					backoff = time.Duration(seconds) * time.Second
+				}
+			}
+			resp.Body.Close()
+			continue
+		}
+		return resp, nil
+	}
+	return nil, lastErr
+}
@@ -3,24 +3,26 @@ package tado
 import (
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"log"
 	"net/http"
 	"net/url"
-	"os"
-	"runtime"
-	"strings"
 	"time"
 
 	"github.com/cblomart/gotadoflux/config"
+	"github.com/cblomart/gotadoflux/secret"
 )
 
 const (
-	TADO_TOKEN        = "https://auth.tado.com/oauth/token"
-	TADO_CLIENTID     = "tado-web-app"
-	TADO_CLIENTSECRET = "wZaRN7rpjn3FoNyF5IFuxg9uMzYJcvOoQ8QWiIqS3hfk6gLhVlG57j5YNoZL2Rtc"
-	TADO_SCOPE        = "home.user"
-	TADO_API          = "https://my.tado.com/api/v2"
+	TADO_TOKEN         = "https://auth.tado.com/oauth/token"
+	TADO_DEVICE_AUTH   = "https://auth.tado.com/oauth/device_authorize"
+	TADO_CLIENTID      = "tado-web-app"
+	TADO_CLIENTSECRET  = "wZaRN7rpjn3FoNyF5IFuxg9uMzYJcvOoQ8QWiIqS3hfk6gLhVlG57j5YNoZL2Rtc"
+	TADO_SCOPE         = "home.user"
+	TADO_API           = "https://my.tado.com/api/v2"
+	TADO_DEVICE_SCOPE  = "offline_access"
+	DEVICE_GRANT_TYPE  = "urn:ietf:params:oauth:grant-type:device_code"
+	AuthModePassword   = "password"
+	AuthModeDeviceFlow = "device"
 )
 
 type TadoError struct {
@@ -37,6 +39,15 @@ type TokenResponse struct {
 	Jti          string `json:"jti"`
 }
 
+type DeviceCodeResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationUri         string `json:"verification_uri"`
+	VerificationUriComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
 type Home struct {
 	Id   int
 	Name string
@@ -91,75 +102,69 @@ type Humidity struct {
 }
 
 type Tado struct {
-	Username         string
-	password         string
-	refreshTokenPath string
-	accessToken      string
-	expires          time.Time
-	refresh          time.Time
-	client           *http.Client
+	Username    string
+	password    string
+	secretStore secret.Store
+	authMode    string
+	maxRetries  int
+	accessToken string
+	expires     time.Time
+	refresh     time.Time
+	client      *http.Client
 }
 
-func NewTado(username, password, refreshTokenPath string) (*Tado, error) {
-	return &Tado{Username: username, password: password, refreshTokenPath: refreshTokenPath, client: &http.Client{
+func NewTado(username, password, authMode string, secretStore secret.Store, httpOpts HTTPOptions) (*Tado, error) {
+	if len(authMode) == 0 {
+		authMode = AuthModePassword
+	}
+	timeout := httpOpts.Timeout
+	if timeout == 0 {
+		timeout = defaultClientTimeout
+	}
+	client := &http.Client{
+		Transport: buildTransport(httpOpts),
+		Timeout:   timeout,
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			return http.ErrUseLastResponse
 		},
-	}}, nil
+	}
+	return &Tado{
+		Username:    username,
+		password:    password,
+		secretStore: secretStore,
+		authMode:    authMode,
+		maxRetries:  httpOpts.MaxRetries,
+		client:      client,
+	}, nil
 }
 
 func ConfigToTado(c *config.Config) (*Tado, error) {
-	return NewTado(c.Username, c.Password, c.RefreshTokenPath)
+	secretStore, err := secret.New(c.TokenStore, c.RefreshTokenPath)
+	if err != nil {
+		return nil, err
+	}
+	httpOpts := HTTPOptions{
+		Proxy:               c.Proxy,
+		NoProxy:             c.NoProxy,
+		Timeout:             c.Timeout.Duration,
+		MaxIdleConns:        c.MaxIdleConns,
+		IdleConnTimeout:     c.IdleConnTimeout.Duration,
+		TLSHandshakeTimeout: c.TLSHandshakeTimeout.Duration,
+		MaxRetries:          c.MaxRetries,
+	}
+	return NewTado(c.Username, c.Password, c.AuthMode, secretStore, httpOpts)
 }
 
 func (t *Tado) SaveRefreshToken(token string) error {
-	if len(token) == 0 {
-		return fmt.Errorf("Cannot save empty refresh token")
-	}
-	encToken := TokenEncrypt(token)
-	err := ioutil.WriteFile(t.refreshTokenPath, []byte(encToken), os.FileMode(int(0666)))
-	if err != nil {
-		return err
-	}
-	return nil
+	return t.secretStore.Save(token)
 }
 
 func (t *Tado) GetRefreshToken() (string, error) {
-	fileInfo, err := os.Stat(t.refreshTokenPath)
-	if err != nil {
-		return "", err
-	}
-	if fileInfo.Mode() != os.FileMode(int(0600)) && runtime.GOOS != "windows" {
-		return "", fmt.Errorf("refresh token cache is not properly protected")
-	}
-	if fileInfo.ModTime().Add(180 * 25 * time.Hour).Before(time.Now()) {
-		return "", nil
-	}
-	if fileInfo.Size() == 0 {
-		return "", nil
-	}
-	token, err := ioutil.ReadFile(t.refreshTokenPath)
-	if err != nil {
-		return "", err
-	}
-	return TokenDecrypt(string(token)), nil
+	return t.secretStore.Get()
 }
 
 func (t *Tado) HasRefreshToken() bool {
-	fileInfo, err := os.Stat(t.refreshTokenPath)
-	if err != nil {
-		return false
-	}
-	if fileInfo.Mode() != os.FileMode(int(0600)) && runtime.GOOS != "windows" {
-		return false
-	}
-	if fileInfo.ModTime().Add(180 * 25 * time.Hour).Before(time.Now()) {
-		return false
-	}
-	if fileInfo.Size() == 0 {
-		return false
-	}
-	return true
+	return t.secretStore.Has()
 }
 
 func (t *Tado) AquireToken() error {
@@ -172,15 +177,13 @@ func (t *Tado) AquireToken() error {
 	params.Set("username", t.Username)
 	params.Set("password", t.password)
 	// token request
-	tokenrequest, err := http.NewRequest("POST", TADO_TOKEN, strings.NewReader(params.Encode()))
-	if err != nil {
-		return err
-	}
-	tokenrequest.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	tokenresponse, err := t.client.Do(tokenrequest)
+	tokenresponse, err := t.doWithRetry("token", func() (*http.Request, error) {
+		return newFormRequest(TADO_TOKEN, params)
+	})
 	if err != nil {
 		return err
 	}
+	defer tokenresponse.Body.Close()
 	// check for error
 	if tokenresponse.StatusCode/100 >= 4 {
 		tadoError := &TadoError{}
@@ -199,6 +202,93 @@ func (t *Tado) AquireToken() error {
 	return nil
 }
 
+// AcquireTokenDeviceFlow authenticates using the OAuth 2.0 device
+// authorization grant (RFC 8628). It requests a device code, prints the
+// verification URL for the user to approve on another device, and polls the
+// token endpoint until the user approves, the device code expires, or the
+// user denies access.
+func (t *Tado) AcquireTokenDeviceFlow() error {
+	// request a device code
+	params := url.Values{}
+	params.Set("client_id", TADO_CLIENTID)
+	params.Set("scope", TADO_DEVICE_SCOPE)
+	deviceresponse, err := t.doWithRetry("device_authorize", func() (*http.Request, error) {
+		return newFormRequest(TADO_DEVICE_AUTH, params)
+	})
+	if err != nil {
+		return err
+	}
+	defer deviceresponse.Body.Close()
+	if deviceresponse.StatusCode/100 >= 4 {
+		tadoError := &TadoError{}
+		json.NewDecoder(deviceresponse.Body).Decode(tadoError)
+		return fmt.Errorf("could not get device code: %s", tadoError.Description)
+	}
+	device := &DeviceCodeResponse{}
+	err = json.NewDecoder(deviceresponse.Body).Decode(device)
+	if err != nil {
+		return err
+	}
+	log.Printf("approve this login at: %s", device.VerificationUriComplete)
+
+	interval := time.Duration(device.Interval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	deadline := time.Now().Add(time.Duration(device.ExpiresIn) * time.Second)
+	for {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("device code expired before approval")
+		}
+		time.Sleep(interval)
+		// poll the token endpoint
+		pollparams := url.Values{}
+		pollparams.Set("client_id", TADO_CLIENTID)
+		pollparams.Set("grant_type", DEVICE_GRANT_TYPE)
+		pollparams.Set("device_code", device.DeviceCode)
+		pollrequest, err := newFormRequest(TADO_TOKEN, pollparams)
+		if err != nil {
+			return err
+		}
+		pollresponse, err := t.client.Do(pollrequest)
+		if err != nil {
+			return err
+		}
+		if pollresponse.StatusCode/100 >= 4 {
+			tadoError := &TadoError{}
+			json.NewDecoder(pollresponse.Body).Decode(tadoError)
+			pollresponse.Body.Close()
+			switch tadoError.Error {
+			case "authorization_pending":
+				continue
+			case "slow_down":
+				interval += 5 * time.Second
+				continue
+			case "expired_token":
+				return fmt.Errorf("device code expired before approval")
+			case "access_denied":
+				return fmt.Errorf("user denied the login request")
+			default:
+				return fmt.Errorf("could not get token: %s", tadoError.Description)
+			}
+		}
+		tokens := &TokenResponse{}
+		err = json.NewDecoder(pollresponse.Body).Decode(tokens)
+		pollresponse.Body.Close()
+		if err != nil {
+			return err
+		}
+		err = t.SaveRefreshToken(tokens.RefreshToken)
+		if err != nil {
+			return fmt.Errorf("Could not save refresh token")
+		}
+		t.expires = time.Now().Add(time.Second * time.Duration(tokens.ExpiresIn))
+		t.refresh = time.Now().Add(time.Second * time.Duration(tokens.ExpiresIn/2))
+		t.accessToken = tokens.AccessToken
+		return nil
+	}
+}
+
 func (t *Tado) RefreshToken() error {
 	// get refresh token
 	if !t.HasRefreshToken() {
@@ -215,15 +305,13 @@ func (t *Tado) RefreshToken() error {
 	params.Set("grant_type", "refresh_token")
 	params.Set("refresh_token", refreshToken)
 	// token request
-	tokenrequest, err := http.NewRequest("POST", TADO_TOKEN, strings.NewReader(params.Encode()))
-	if err != nil {
-		return err
-	}
-	tokenrequest.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	tokenresponse, err := t.client.Do(tokenrequest)
+	tokenresponse, err := t.doWithRetry("token", func() (*http.Request, error) {
+		return newFormRequest(TADO_TOKEN, params)
+	})
 	if err != nil {
 		return err
 	}
+	defer tokenresponse.Body.Close()
 	// check for error
 	if tokenresponse.StatusCode/100 >= 4 {
 		tadoError := &TadoError{}
@@ -242,6 +330,15 @@ func (t *Tado) RefreshToken() error {
 	return nil
 }
 
+// acquireToken obtains a brand new access/refresh token pair using whichever
+// flow the Tado client was configured with.
+func (t *Tado) acquireToken() error {
+	if t.authMode == AuthModeDeviceFlow {
+		return t.AcquireTokenDeviceFlow()
+	}
+	return t.AquireToken()
+}
+
 func (t *Tado) AuthCheck() error {
 	if len(t.accessToken) > 0 {
 		// we have an access token
@@ -260,7 +357,7 @@ func (t *Tado) AuthCheck() error {
 				log.Println("Access token refreshed")
 			} else {
 				// we don't have a refresh token
-				err := t.AquireToken()
+				err := t.acquireToken()
 				if err != nil && t.expires.Before(time.Now()) {
 					// token expired and can't aquire a new one
 					return err
@@ -280,7 +377,7 @@ func (t *Tado) AuthCheck() error {
 			log.Println("New access token from refresh")
 		} else {
 			// we don't have a refresh token
-			err := t.AquireToken()
+			err := t.acquireToken()
 			if err != nil {
 				return err
 			}
@@ -300,16 +397,13 @@ func (t *Tado) GetHome() (*Home, error) {
 		return nil, err
 	}
 	// create request
-	request, err := http.NewRequest("GET", fmt.Sprintf("%s/me", TADO_API), nil)
-	if err != nil {
-		return nil, err
-	}
-	// set authentication
-	request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", t.accessToken))
-	response, err := t.client.Do(request)
+	response, err := t.doWithRetry("me", func() (*http.Request, error) {
+		return newAuthorizedGet(fmt.Sprintf("%s/me", TADO_API), t.accessToken)
+	})
 	if err != nil {
 		return nil, err
 	}
+	defer response.Body.Close()
 	// check for error
 	if response.StatusCode/100 >= 4 {
 		tadoError := &TadoError{}
@@ -343,16 +437,13 @@ func (t *Tado) GetZoneStates(id int) (*ZoneStatesResponse, error) {
 		return nil, err
 	}
 	// create request
-	request, err := http.NewRequest("GET", fmt.Sprintf("%s/homes/%d/zoneStates", TADO_API, id), nil)
-	if err != nil {
-		return nil, err
-	}
-	// set authentication
-	request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", t.accessToken))
-	response, err := t.client.Do(request)
+	response, err := t.doWithRetry("zoneStates", func() (*http.Request, error) {
+		return newAuthorizedGet(fmt.Sprintf("%s/homes/%d/zoneStates", TADO_API, id), t.accessToken)
+	})
 	if err != nil {
 		return nil, err
 	}
+	defer response.Body.Close()
 	// check for error
 	if response.StatusCode/100 >= 4 {
 		tadoError := &TadoError{}
@@ -378,16 +469,13 @@ func (t *Tado) GetZones(id int) ([]Zone, error) {
 		return nil, err
 	}
 	// create request
-	request, err := http.NewRequest("GET", fmt.Sprintf("%s/homes/%d/zones", TADO_API, id), nil)
-	if err != nil {
-		return nil, err
-	}
-	// set authentication
-	request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", t.accessToken))
-	response, err := t.client.Do(request)
+	response, err := t.doWithRetry("zones", func() (*http.Request, error) {
+		return newAuthorizedGet(fmt.Sprintf("%s/homes/%d/zones", TADO_API, id), t.accessToken)
+	})
 	if err != nil {
 		return nil, err
 	}
+	defer response.Body.Close()
 	// check for error
 	if response.StatusCode/100 >= 4 {
 		tadoError := &TadoError{}
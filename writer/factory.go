@@ -0,0 +1,34 @@
+package writer
+
+import (
+	"fmt"
+
+	"github.com/cblomart/gotadoflux/config"
+)
+
+const (
+	VersionV1 = "v1"
+	VersionV2 = "v2"
+)
+
+// NewInflux builds the InfluxDB writer matching conf.Version. When Version
+// is empty it is inferred from the presence of Token/Bucket (v2) vs
+// Database/Username (v1).
+func NewInflux(conf config.Influx) (Writer, error) {
+	version := conf.Version
+	if len(version) == 0 {
+		if len(conf.Token) > 0 || len(conf.Bucket) > 0 {
+			version = VersionV2
+		} else {
+			version = VersionV1
+		}
+	}
+	switch version {
+	case VersionV2:
+		return NewInfluxV2Writer(conf.Url, conf.Token, conf.Org, conf.Bucket)
+	case VersionV1:
+		return NewInfluxV1Writer(conf.Url, conf.Database, conf.Username, conf.Password)
+	default:
+		return nil, fmt.Errorf("unknown influx version: %s", version)
+	}
+}
@@ -0,0 +1,54 @@
+package writer
+
+import (
+	"fmt"
+	"time"
+
+	client "github.com/influxdata/influxdb1-client/v2"
+)
+
+// InfluxV1Writer writes points to an InfluxDB 1.x (or 2.x in 1.x
+// compatibility mode) server using database/username/password auth.
+type InfluxV1Writer struct {
+	client   client.Client
+	database string
+}
+
+func NewInfluxV1Writer(url, database, username, password string) (*InfluxV1Writer, error) {
+	c, err := client.NewHTTPClient(client.HTTPConfig{
+		Addr:     url,
+		Username: username,
+		Password: password,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &InfluxV1Writer{client: c, database: database}, nil
+}
+
+func (w *InfluxV1Writer) Write(points []Point) error {
+	bps, err := client.NewBatchPoints(client.BatchPointsConfig{
+		Precision: "s",
+		Database:  w.database,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create batchpoints: %s", err)
+	}
+	for _, point := range points {
+		p, err := client.NewPoint(point.Name, point.Tags, point.Fields, point.Time)
+		if err != nil {
+			return fmt.Errorf("could not create point for %s: %s", point.Name, err)
+		}
+		bps.AddPoint(p)
+	}
+	return w.client.Write(bps)
+}
+
+func (w *InfluxV1Writer) Ping() error {
+	_, _, err := w.client.Ping(5 * time.Second)
+	return err
+}
+
+func (w *InfluxV1Writer) Close() error {
+	return w.client.Close()
+}
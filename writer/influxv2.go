@@ -0,0 +1,41 @@
+package writer
+
+import (
+	"context"
+
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/influxdata/influxdb-client-go/v2/api"
+	influxdb2Write "github.com/influxdata/influxdb-client-go/v2/api/write"
+)
+
+// InfluxV2Writer writes points to an InfluxDB 2.x server using token auth
+// against an org/bucket pair.
+type InfluxV2Writer struct {
+	client influxdb2.Client
+	writer api.WriteAPIBlocking
+}
+
+func NewInfluxV2Writer(url, token, org, bucket string) (*InfluxV2Writer, error) {
+	c := influxdb2.NewClient(url, token)
+	return &InfluxV2Writer{client: c, writer: c.WriteAPIBlocking(org, bucket)}, nil
+}
+
+func (w *InfluxV2Writer) Write(points []Point) error {
+	for _, point := range points {
+		p := influxdb2Write.NewPoint(point.Name, point.Tags, point.Fields, point.Time)
+		if err := w.writer.WritePoint(context.Background(), p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *InfluxV2Writer) Ping() error {
+	_, err := w.client.Ping(context.Background())
+	return err
+}
+
+func (w *InfluxV2Writer) Close() error {
+	w.client.Close()
+	return nil
+}
@@ -0,0 +1,144 @@
+package writer
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/cblomart/gotadoflux/config"
+)
+
+// MQTTWriter publishes each collected reading to MQTT, and advertises newly
+// seen zones to Home Assistant via its MQTT discovery convention.
+type MQTTWriter struct {
+	client      mqtt.Client
+	topicPrefix string
+	qos         byte
+	retain      bool
+	discovered  map[string]bool
+}
+
+type mqttState struct {
+	Temperature float32   `json:"temperature"`
+	Humidity    float32   `json:"humidity"`
+	Power       float32   `json:"power"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+type mqttDiscovery struct {
+	Name              string `json:"name"`
+	StateTopic        string `json:"state_topic"`
+	ValueTemplate     string `json:"value_template"`
+	UnitOfMeasurement string `json:"unit_of_measurement"`
+	UniqueID          string `json:"unique_id"`
+}
+
+func NewMQTTWriter(conf config.MQTT) (*MQTTWriter, error) {
+	opts := mqtt.NewClientOptions().AddBroker(conf.Broker).SetClientID(conf.ClientID)
+	if len(conf.Username) > 0 {
+		opts.SetUsername(conf.Username)
+		opts.SetPassword(conf.Password)
+	}
+	topicPrefix := conf.TopicPrefix
+	if len(topicPrefix) == 0 {
+		topicPrefix = "tado"
+	}
+	return &MQTTWriter{
+		client:      mqtt.NewClient(opts),
+		topicPrefix: topicPrefix,
+		qos:         conf.QoS,
+		retain:      conf.Retain,
+		discovered:  map[string]bool{},
+	}, nil
+}
+
+func (w *MQTTWriter) Ping() error {
+	if w.client.IsConnected() {
+		return nil
+	}
+	token := w.client.Connect()
+	token.Wait()
+	return token.Error()
+}
+
+func (w *MQTTWriter) Write(points []Point) error {
+	for _, point := range points {
+		homeId := point.Tags["homeId"]
+		homeName := point.Tags["homeName"]
+		zoneId := point.Tags["zoneId"]
+		zoneName := point.Tags["zoneName"]
+
+		if err := w.discover(homeId, homeName, zoneId, zoneName); err != nil {
+			return err
+		}
+
+		temperature, _ := point.Fields["temperature"].(float32)
+		humidity, _ := point.Fields["humidity"].(float32)
+		power, _ := point.Fields["power"].(float32)
+		state := mqttState{
+			Temperature: temperature,
+			Humidity:    humidity,
+			Power:       power,
+			Timestamp:   point.Time,
+		}
+		payload, err := json.Marshal(state)
+		if err != nil {
+			return err
+		}
+		topic := fmt.Sprintf("%s/%s/%s/state", w.topicPrefix, homeName, zoneName)
+		token := w.client.Publish(topic, w.qos, w.retain, payload)
+		token.Wait()
+		if err := token.Error(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// discover publishes Home Assistant MQTT discovery messages for a zone the
+// first time it is seen, so the zone's sensors auto-appear in HA.
+func (w *MQTTWriter) discover(homeId, homeName, zoneId, zoneName string) error {
+	key := fmt.Sprintf("%s_%s", homeId, zoneId)
+	if w.discovered[key] {
+		return nil
+	}
+	stateTopic := fmt.Sprintf("%s/%s/%s/state", w.topicPrefix, homeName, zoneName)
+	sensors := []struct {
+		suffix string
+		unit   string
+		field  string
+	}{
+		{"temperature", "°C", "temperature"},
+		{"humidity", "%", "humidity"},
+		{"heating_power", "%", "power"},
+	}
+	for _, sensor := range sensors {
+		uniqueId := fmt.Sprintf("%s_%s_%s", homeId, zoneId, sensor.suffix)
+		discovery := mqttDiscovery{
+			Name:              fmt.Sprintf("%s %s %s", homeName, zoneName, sensor.suffix),
+			StateTopic:        stateTopic,
+			ValueTemplate:     fmt.Sprintf("{{ value_json.%s }}", sensor.field),
+			UnitOfMeasurement: sensor.unit,
+			UniqueID:          uniqueId,
+		}
+		payload, err := json.Marshal(discovery)
+		if err != nil {
+			return err
+		}
+		topic := fmt.Sprintf("homeassistant/sensor/%s/config", uniqueId)
+		token := w.client.Publish(topic, w.qos, true, payload)
+		token.Wait()
+		if err := token.Error(); err != nil {
+			return err
+		}
+	}
+	w.discovered[key] = true
+	return nil
+}
+
+func (w *MQTTWriter) Close() error {
+	w.client.Disconnect(250)
+	return nil
+}
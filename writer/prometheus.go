@@ -0,0 +1,38 @@
+package writer
+
+import (
+	"github.com/cblomart/gotadoflux/metrics"
+)
+
+// PrometheusWriter updates the tado_zone_* gauges from collected points so
+// they can be scraped instead of (or alongside) being pushed to InfluxDB.
+type PrometheusWriter struct{}
+
+func NewPrometheusWriter() *PrometheusWriter {
+	return &PrometheusWriter{}
+}
+
+func (w *PrometheusWriter) Write(points []Point) error {
+	for _, point := range points {
+		labels := []string{point.Tags["homeName"], point.Tags["zoneName"], point.Tags["zoneId"]}
+		if temperature, ok := point.Fields["temperature"].(float32); ok {
+			metrics.ZoneTemperature.WithLabelValues(labels...).Set(float64(temperature))
+		}
+		if humidity, ok := point.Fields["humidity"].(float32); ok {
+			metrics.ZoneHumidity.WithLabelValues(labels...).Set(float64(humidity))
+		}
+		if power, ok := point.Fields["power"].(float32); ok {
+			metrics.ZoneHeatingPower.WithLabelValues(labels...).Set(float64(power))
+		}
+		metrics.ZoneLastUpdate.WithLabelValues(labels...).Set(float64(point.Time.Unix()))
+	}
+	return nil
+}
+
+func (w *PrometheusWriter) Ping() error {
+	return nil
+}
+
+func (w *PrometheusWriter) Close() error {
+	return nil
+}
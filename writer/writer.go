@@ -0,0 +1,22 @@
+// Package writer provides pluggable output backends for collected tado
+// readings.
+package writer
+
+import "time"
+
+// Point is a single measurement collected from a tado zone, independent of
+// the backend it will eventually be written to.
+type Point struct {
+	Name   string
+	Tags   map[string]string
+	Fields map[string]interface{}
+	Time   time.Time
+}
+
+// Writer is implemented by each output backend (InfluxDB v1, InfluxDB v2,
+// MQTT, ...).
+type Writer interface {
+	Write(points []Point) error
+	Ping() error
+	Close() error
+}